@@ -0,0 +1,233 @@
+/*
+ *
+ * Copyright 2024 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clusterimpl
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/internal/grpclog"
+	"google.golang.org/grpc/xds/internal/xdsclient/xdsresource"
+)
+
+// healthWatcher is notified whenever active health checking changes its
+// verdict for an endpoint. endpointHealthState implements this, invoking its
+// onChange callback so the balancer can recompute its picker whenever an
+// endpoint flips in or out of the active set.
+type healthWatcher interface {
+	onActiveHealthUpdate(addr string, healthy bool)
+}
+
+// activeHealthChecker runs periodic grpc.health.v1.Health/Check RPCs against
+// a set of endpoints and reports the result to a healthWatcher. The caller
+// combines its verdict with the EDS-reported HealthStatus: an endpoint is
+// only picked when both sources say HEALTHY.
+type activeHealthChecker struct {
+	interval           time.Duration
+	service            string
+	healthyThreshold   uint32
+	unhealthyThreshold uint32
+	creds              credentials.TransportCredentials
+	watcher            healthWatcher
+	logger             *grpclog.PrefixLogger
+
+	mu    sync.Mutex
+	stops map[string]activeCheck
+}
+
+// activeCheck records the dial target a running check was started with, so
+// addEndpoint can detect when ep's health-check-relevant fields changed and
+// restart the check against the new target instead of treating it as a
+// duplicate of the one already running.
+type activeCheck struct {
+	dialAddr  string
+	authority string
+	cancel    context.CancelFunc
+}
+
+// newActiveHealthChecker creates an activeHealthChecker from the cluster's
+// HealthCheck config: it checks the given service name every interval, and
+// requires healthyThreshold (resp. unhealthyThreshold) consecutive
+// successful (resp. failed) checks before flipping an endpoint's verdict.
+// Thresholds of zero are treated as one, flipping on the first check. creds
+// is used to dial endpoints; it defaults to insecure when nil, since the
+// health check RPC targets the same backend the data-plane RPCs already
+// trust.
+func newActiveHealthChecker(interval time.Duration, service string, healthyThreshold, unhealthyThreshold uint32, creds credentials.TransportCredentials, watcher healthWatcher, logger *grpclog.PrefixLogger) *activeHealthChecker {
+	if healthyThreshold == 0 {
+		healthyThreshold = 1
+	}
+	if unhealthyThreshold == 0 {
+		unhealthyThreshold = 1
+	}
+	if creds == nil {
+		creds = insecure.NewCredentials()
+	}
+	return &activeHealthChecker{
+		interval:           interval,
+		service:            service,
+		healthyThreshold:   healthyThreshold,
+		unhealthyThreshold: unhealthyThreshold,
+		creds:              creds,
+		watcher:            watcher,
+		logger:             logger,
+		stops:              make(map[string]activeCheck),
+	}
+}
+
+// addEndpoint starts actively health checking ep. It is a no-op if ep is
+// already being checked against the same dial target; if ep's
+// HealthCheckPort or Hostname changed since the check was started, the old
+// check is stopped and a new one started against the updated target. The
+// health check RPC dials ep.HealthCheckPort when set (falling back to
+// ep.Address's own port), and uses ep.Hostname as the :authority of the RPC
+// when set, matching Envoy's health_check_config semantics.
+func (c *activeHealthChecker) addEndpoint(ep xdsresource.Endpoint) {
+	addr := ep.Address
+	if ep.HealthCheckPort != 0 {
+		if host, _, err := net.SplitHostPort(ep.Address); err == nil {
+			addr = net.JoinHostPort(host, strconv.Itoa(int(ep.HealthCheckPort)))
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if existing, ok := c.stops[ep.Address]; ok {
+		if existing.dialAddr == addr && existing.authority == ep.Hostname {
+			return
+		}
+		existing.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c.stops[ep.Address] = activeCheck{dialAddr: addr, authority: ep.Hostname, cancel: cancel}
+	go c.watch(ctx, ep.Address, addr, ep.Hostname)
+}
+
+// removeEndpoint stops actively health checking the endpoint with the given
+// (original, not health-check-port-adjusted) address.
+func (c *activeHealthChecker) removeEndpoint(addr string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if check, ok := c.stops[addr]; ok {
+		check.cancel()
+		delete(c.stops, addr)
+	}
+}
+
+// close stops all in-flight health checks.
+func (c *activeHealthChecker) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, check := range c.stops {
+		check.cancel()
+	}
+	c.stops = make(map[string]activeCheck)
+}
+
+// watch dials dialAddr and issues a Health/Check RPC every c.interval until
+// ctx is canceled, reporting verdict flips against reportAddr (the
+// EDS-visible address other components key off of).
+func (c *activeHealthChecker) watch(ctx context.Context, reportAddr, dialAddr, authority string) {
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(c.creds)}
+	if authority != "" {
+		opts = append(opts, grpc.WithAuthority(authority))
+	}
+	cc, err := grpc.NewClient(dialAddr, opts...)
+	if err != nil {
+		c.logger.Warningf("active health check: failed to create client for %q: %v", dialAddr, err)
+		return
+	}
+	defer cc.Close()
+	client := healthpb.NewHealthClient(cc)
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	var (
+		verdict     = verdictUnknown
+		consecutive uint32
+		lastSuccess bool
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		rCtx, rCancel := context.WithTimeout(ctx, c.interval)
+		resp, err := client.Check(rCtx, &healthpb.HealthCheckRequest{Service: c.service})
+		rCancel()
+		success := err == nil && resp.GetStatus() == healthpb.HealthCheckResponse_SERVING
+		// While the verdict is unknown, both outcomes count towards a flip, so
+		// a change of outcome restarts the run of agreeing results.
+		if verdict == verdictUnknown && consecutive > 0 && success != lastSuccess {
+			consecutive = 0
+		}
+		lastSuccess = success
+
+		newVerdict, newConsecutive := nextVerdict(verdict, consecutive, success, c.healthyThreshold, c.unhealthyThreshold)
+		consecutive = newConsecutive
+		if newVerdict != verdict {
+			verdict = newVerdict
+			c.watcher.onActiveHealthUpdate(reportAddr, verdict == verdictHealthy)
+		}
+	}
+}
+
+// healthVerdict is the active checker's settled opinion of an endpoint.
+// verdictUnknown is the state before any check has settled (i.e. before
+// healthyThreshold or unhealthyThreshold consecutive results have agreed),
+// and is distinct from both verdictHealthy and verdictUnhealthy so that an
+// endpoint which is unhealthy from the very first check is still reported
+// once it settles, instead of being mistaken for "no change from the
+// zero-value default".
+type healthVerdict int
+
+const (
+	verdictUnknown healthVerdict = iota
+	verdictHealthy
+	verdictUnhealthy
+)
+
+// nextVerdict applies Envoy's healthy_threshold/unhealthy_threshold
+// semantics: a status change only takes effect after the configured number
+// of consecutive checks agree on the new status. It returns the (possibly
+// unchanged) health verdict and the updated consecutive-result counter.
+func nextVerdict(verdict healthVerdict, consecutive uint32, success bool, healthyThreshold, unhealthyThreshold uint32) (healthVerdict, uint32) {
+	target := verdictUnhealthy
+	threshold := unhealthyThreshold
+	if success {
+		target = verdictHealthy
+		threshold = healthyThreshold
+	}
+	if verdict == target {
+		// The result agrees with the current verdict; nothing is trending.
+		return verdict, 0
+	}
+	consecutive++
+	if consecutive >= threshold {
+		return target, 0
+	}
+	return verdict, consecutive
+}
@@ -0,0 +1,157 @@
+/*
+ *
+ * Copyright 2024 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clusterimpl
+
+import (
+	"sync"
+	"time"
+
+	v3corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/internal/grpclog"
+	"google.golang.org/grpc/xds/internal/xdsclient/xdsresource"
+)
+
+// endpointHealthChecker is the subset of *activeHealthChecker that
+// endpointHealthState depends on, so tests can substitute a fake.
+type endpointHealthChecker interface {
+	addEndpoint(ep xdsresource.Endpoint)
+	removeEndpoint(addr string)
+	close()
+}
+
+// healthCheckConfig is the parsed form of a cluster's HealthCheck message
+// relevant to active health checking.
+type healthCheckConfig struct {
+	interval           time.Duration
+	service            string
+	healthyThreshold   uint32
+	unhealthyThreshold uint32
+}
+
+// parseHealthCheckConfig extracts the grpc_health_check fields from a
+// cluster's HealthCheck message. ok is false when hc is nil or does not
+// configure a gRPC health check, in which case active health checking
+// should not be enabled for the cluster.
+func parseHealthCheckConfig(hc *v3corepb.HealthCheck) (cfg healthCheckConfig, ok bool) {
+	if hc.GetGrpcHealthCheck() == nil {
+		return healthCheckConfig{}, false
+	}
+	interval := hc.GetInterval().AsDuration()
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return healthCheckConfig{
+		interval:           interval,
+		service:            hc.GetGrpcHealthCheck().GetServiceName(),
+		healthyThreshold:   hc.GetHealthyThreshold().GetValue(),
+		unhealthyThreshold: hc.GetUnhealthyThreshold().GetValue(),
+	}, true
+}
+
+// endpointHealthState tracks the active-health-check verdict for every
+// endpoint of a cluster and combines it with the EDS-reported HealthStatus,
+// so that the balancer's picker only routes to an endpoint when both
+// sources agree it is HEALTHY. It implements healthWatcher.
+type endpointHealthState struct {
+	checker  endpointHealthChecker // nil when the cluster has no HealthCheck configured
+	onChange func()
+
+	mu      sync.Mutex
+	healthy map[string]bool // addr -> active-health verdict; absent means "not yet determined", treated as healthy
+	checked map[string]bool // addresses handed to checker by the last updateEndpoints call
+}
+
+// newEndpointHealthState creates an endpointHealthState for a cluster. If hc
+// does not configure gRPC active health checking, active checking is
+// disabled and isHealthy falls back to the EDS-reported status alone.
+// onChange is invoked (from a health-checking goroutine) whenever an
+// endpoint's combined health verdict may have changed, so the caller can
+// regenerate its picker. creds is used to dial endpoints for the health
+// check RPC; it defaults to insecure when nil.
+func newEndpointHealthState(hc *v3corepb.HealthCheck, creds credentials.TransportCredentials, onChange func(), logger *grpclog.PrefixLogger) *endpointHealthState {
+	s := &endpointHealthState{onChange: onChange, healthy: make(map[string]bool)}
+	if cfg, ok := parseHealthCheckConfig(hc); ok {
+		s.checker = newActiveHealthChecker(cfg.interval, cfg.service, cfg.healthyThreshold, cfg.unhealthyThreshold, creds, s, logger)
+	}
+	return s
+}
+
+// onActiveHealthUpdate implements healthWatcher.
+func (s *endpointHealthState) onActiveHealthUpdate(addr string, healthy bool) {
+	s.mu.Lock()
+	s.healthy[addr] = healthy
+	s.mu.Unlock()
+	if s.onChange != nil {
+		s.onChange()
+	}
+}
+
+// updateEndpoints reconciles the set of actively checked endpoints with eps,
+// starting checks for new endpoints and stopping them for endpoints that are
+// no longer part of the cluster. It is a no-op when active health checking
+// is not configured for this cluster.
+func (s *endpointHealthState) updateEndpoints(eps []xdsresource.Endpoint) {
+	if s.checker == nil {
+		return
+	}
+	want := make(map[string]bool, len(eps))
+	for _, ep := range eps {
+		want[ep.Address] = true
+		s.checker.addEndpoint(ep)
+	}
+	s.mu.Lock()
+	var stale []string
+	for addr := range s.checked {
+		if !want[addr] {
+			stale = append(stale, addr)
+		}
+	}
+	s.checked = want
+	for _, addr := range stale {
+		delete(s.healthy, addr)
+	}
+	s.mu.Unlock()
+	for _, addr := range stale {
+		s.checker.removeEndpoint(addr)
+	}
+}
+
+// isHealthy reports whether ep should be considered ready to pick: the
+// EDS-reported status must be HEALTHY (or UNKNOWN, which EDS treats as
+// implicitly healthy) and, if active health checking is configured, the
+// active checker must not have observed it as unhealthy.
+func (s *endpointHealthState) isHealthy(ep xdsresource.Endpoint) bool {
+	if ep.HealthStatus != xdsresource.EndpointHealthStatusHealthy && ep.HealthStatus != xdsresource.EndpointHealthStatusUnknown {
+		return false
+	}
+	if s.checker == nil {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	healthy, ok := s.healthy[ep.Address]
+	return !ok || healthy
+}
+
+// close stops all active health checking.
+func (s *endpointHealthState) close() {
+	if s.checker != nil {
+		s.checker.close()
+	}
+}
@@ -0,0 +1,105 @@
+/*
+ *
+ * Copyright 2024 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clusterimpl
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/xds/internal/xdsclient/xdsresource"
+)
+
+func TestNextVerdict(t *testing.T) {
+	tests := []struct {
+		name               string
+		verdict            healthVerdict
+		consecutive        uint32
+		success            bool
+		healthyThreshold   uint32
+		unhealthyThreshold uint32
+		wantVerdict        healthVerdict
+		wantConsecutive    uint32
+	}{
+		{name: "agrees with current verdict resets counter", verdict: verdictHealthy, consecutive: 3, success: true, healthyThreshold: 2, unhealthyThreshold: 2, wantVerdict: verdictHealthy, wantConsecutive: 0},
+		{name: "single failure below threshold does not flip", verdict: verdictHealthy, consecutive: 0, success: false, healthyThreshold: 2, unhealthyThreshold: 3, wantVerdict: verdictHealthy, wantConsecutive: 1},
+		{name: "failures reach threshold and flip", verdict: verdictHealthy, consecutive: 2, success: false, healthyThreshold: 2, unhealthyThreshold: 3, wantVerdict: verdictUnhealthy, wantConsecutive: 0},
+		{name: "successes reach threshold and flip back", verdict: verdictUnhealthy, consecutive: 1, success: true, healthyThreshold: 2, unhealthyThreshold: 3, wantVerdict: verdictHealthy, wantConsecutive: 0},
+		{name: "first check fails and settles immediately at threshold one", verdict: verdictUnknown, consecutive: 0, success: false, healthyThreshold: 1, unhealthyThreshold: 1, wantVerdict: verdictUnhealthy, wantConsecutive: 0},
+		{name: "first check fails below threshold stays unknown", verdict: verdictUnknown, consecutive: 0, success: false, healthyThreshold: 1, unhealthyThreshold: 2, wantVerdict: verdictUnknown, wantConsecutive: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotVerdict, gotConsecutive := nextVerdict(tt.verdict, tt.consecutive, tt.success, tt.healthyThreshold, tt.unhealthyThreshold)
+			if gotVerdict != tt.wantVerdict || gotConsecutive != tt.wantConsecutive {
+				t.Errorf("nextVerdict(%v, %d, %v, %d, %d) = (%v, %d), want (%v, %d)", tt.verdict, tt.consecutive, tt.success, tt.healthyThreshold, tt.unhealthyThreshold, gotVerdict, gotConsecutive, tt.wantVerdict, tt.wantConsecutive)
+			}
+		})
+	}
+}
+
+// fakeHealthChecker substitutes for *activeHealthChecker in tests, recording
+// which endpoints are under test without dialing any real connections.
+type fakeHealthChecker struct {
+	added   map[string]bool
+	removed []string
+}
+
+func newFakeHealthChecker() *fakeHealthChecker {
+	return &fakeHealthChecker{added: make(map[string]bool)}
+}
+
+func (f *fakeHealthChecker) addEndpoint(ep xdsresource.Endpoint) { f.added[ep.Address] = true }
+func (f *fakeHealthChecker) removeEndpoint(addr string) {
+	delete(f.added, addr)
+	f.removed = append(f.removed, addr)
+}
+func (f *fakeHealthChecker) close() {}
+
+func TestEndpointHealthStateUpdateEndpoints(t *testing.T) {
+	fake := newFakeHealthChecker()
+	s := &endpointHealthState{checker: fake, healthy: make(map[string]bool)}
+
+	s.updateEndpoints([]xdsresource.Endpoint{{Address: "a:1"}, {Address: "b:1"}})
+	if !fake.added["a:1"] || !fake.added["b:1"] {
+		t.Fatalf("expected a:1 and b:1 to be added, got %v", fake.added)
+	}
+
+	s.onActiveHealthUpdate("a:1", false)
+	s.onActiveHealthUpdate("b:1", true)
+
+	// Endpoint a:1 is EDS-healthy but actively unhealthy: must not be picked.
+	if s.isHealthy(xdsresource.Endpoint{Address: "a:1", HealthStatus: xdsresource.EndpointHealthStatusHealthy}) {
+		t.Errorf("isHealthy(a:1) = true, want false")
+	}
+	// Endpoint b:1 is healthy on both counts.
+	if !s.isHealthy(xdsresource.Endpoint{Address: "b:1", HealthStatus: xdsresource.EndpointHealthStatusHealthy}) {
+		t.Errorf("isHealthy(b:1) = false, want true")
+	}
+
+	// b:1 drops out of the cluster: checking should stop and its verdict
+	// should no longer influence isHealthy.
+	s.updateEndpoints([]xdsresource.Endpoint{{Address: "a:1"}})
+	found := false
+	for _, addr := range fake.removed {
+		if addr == "b:1" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected b:1 to be removed from active checking, removed = %v", fake.removed)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+ *
+ * Copyright 2024 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xdsresource
+
+// Equal reports whether e and other represent the same endpoint, including
+// the ORCA metadata hints and health-checking fields. The resource cache
+// calls this (via EndpointsUpdate.Equal) to decide whether an EDS update
+// actually changed anything; an update that only flips a metadata value must
+// compare unequal here or it is dropped as a duplicate before ever reaching
+// the watcher.
+func (e Endpoint) Equal(other Endpoint) bool {
+	return e.HealthStatus == other.HealthStatus &&
+		e.Address == other.Address &&
+		e.ResolverType == other.ResolverType &&
+		e.Weight == other.Weight &&
+		e.Hostname == other.Hostname &&
+		e.HealthCheckPort == other.HealthCheckPort &&
+		e.ApplicationUtilization == other.ApplicationUtilization &&
+		e.CPUUtilization == other.CPUUtilization &&
+		e.EPS == other.EPS &&
+		endpointMetadataEqual(e.Metadata, other.Metadata)
+}
+
+func endpointMetadataEqual(a, b map[string]any) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal reports whether l and other are the same locality, including every
+// endpoint's metadata and health-checking fields.
+func (l Locality) Equal(other Locality) bool {
+	if l.ID != other.ID || l.Weight != other.Weight || l.Priority != other.Priority || l.LEDSCollection != other.LEDSCollection {
+		return false
+	}
+	if len(l.Endpoints) != len(other.Endpoints) {
+		return false
+	}
+	for i := range l.Endpoints {
+		if !l.Endpoints[i].Equal(other.Endpoints[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal reports whether u and other represent the same EndpointsUpdate, down
+// to per-endpoint metadata. Raw is deliberately excluded, matching how the
+// rest of this comparison is only concerned with the fields watchers
+// actually observe.
+func (u EndpointsUpdate) Equal(other EndpointsUpdate) bool {
+	if len(u.Drops) != len(other.Drops) || len(u.Localities) != len(other.Localities) {
+		return false
+	}
+	for i := range u.Drops {
+		if u.Drops[i] != other.Drops[i] {
+			return false
+		}
+	}
+	for i := range u.Localities {
+		if !u.Localities[i].Equal(other.Localities[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,105 @@
+/*
+ *
+ * Copyright 2024 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xdsresource
+
+import (
+	"fmt"
+	"strings"
+
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+)
+
+// ResourceErrorCode is a machine-readable identifier for the kind of
+// validation failure found while parsing a single xDS resource.
+type ResourceErrorCode int
+
+const (
+	// ResourceErrorCodeUnknown is the zero value and should not be used.
+	ResourceErrorCodeUnknown ResourceErrorCode = iota
+	// ResourceErrorCodeMissingLocalityID indicates a locality without an ID.
+	ResourceErrorCodeMissingLocalityID
+	// ResourceErrorCodeDuplicateLocality indicates two localities at the
+	// same priority with the same ID.
+	ResourceErrorCodeDuplicateLocality
+	// ResourceErrorCodeZeroWeightEndpoint indicates an endpoint advertising
+	// a load_balancing_weight of zero.
+	ResourceErrorCodeZeroWeightEndpoint
+	// ResourceErrorCodeInvalidAddress indicates an endpoint whose address
+	// could not be parsed into a dialable form.
+	ResourceErrorCodeInvalidAddress
+	// ResourceErrorCodeInvalidFractionalPercent indicates a drop_overloads
+	// entry with an unknown FractionalPercent denominator.
+	ResourceErrorCodeInvalidFractionalPercent
+	// ResourceErrorCodeMissingPriority indicates a gap in the set of
+	// priorities used across localities.
+	ResourceErrorCodeMissingPriority
+	// ResourceErrorCodeMissingLEDSCollection indicates a locality with a
+	// leds_cluster_locality_config but no leds_collection_name.
+	ResourceErrorCodeMissingLEDSCollection
+)
+
+// ResourceError describes a single validation failure encountered while
+// parsing one xDS resource. Path pinpoints the failure within the resource
+// as a JSON pointer (RFC 6901), e.g. "/endpoints/0/lb_endpoints/2", so that a
+// control plane receiving it in a NACK's error_detail can identify exactly
+// which endpoint or locality was rejected.
+type ResourceError struct {
+	// Name is the name of the resource the failure was found in.
+	Name string
+	// Path is a JSON pointer into the resource proto.
+	Path string
+	// Code is a machine-readable identifier for the kind of failure.
+	Code ResourceErrorCode
+	// Detail is a human-readable description of the failure.
+	Detail string
+}
+
+// Error implements the error interface.
+func (e *ResourceError) Error() string {
+	return fmt.Sprintf("resource %q at %q: %s", e.Name, e.Path, e.Detail)
+}
+
+// MultiResourceError aggregates every ResourceError found while parsing a
+// single xDS resource. The xdsClient's ADS stream handling unwraps this to
+// populate the NACK's error_detail with one entry per validation failure,
+// instead of only reporting the first one encountered.
+type MultiResourceError struct {
+	Errors []*ResourceError
+}
+
+// Error implements the error interface.
+func (e *MultiResourceError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, re := range e.Errors {
+		msgs[i] = re.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ErrorDetail renders the full set of per-resource errors into the
+// google.rpc.Status the xdsClient's ADS stream handling sets on a
+// DiscoveryRequest's error_detail field when NACK-ing a resource, so that
+// the control plane receiving the NACK can see every validation failure,
+// not just the first.
+func (e *MultiResourceError) ErrorDetail() *spb.Status {
+	return &spb.Status{
+		Code:    int32(codes.InvalidArgument),
+		Message: e.Error(),
+	}
+}
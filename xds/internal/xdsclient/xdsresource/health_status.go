@@ -0,0 +1,47 @@
+/*
+ *
+ * Copyright 2024 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xdsresource
+
+// EndpointHealthStatus is the health status of an endpoint, as reported by
+// EDS.
+type EndpointHealthStatus int32
+
+// EndpointHealthStatus values mirror the numeric values of Envoy's
+// envoy.config.core.v3.HealthStatus enum, which is what LbEndpoint.health_status
+// is cast from in parseEndpoints.
+const (
+	// EndpointHealthStatusUnknown means the health status of the endpoint
+	// was not reported; EDS treats this the same as HEALTHY.
+	EndpointHealthStatusUnknown EndpointHealthStatus = iota
+	// EndpointHealthStatusHealthy means the endpoint is healthy and can be
+	// used for load balancing.
+	EndpointHealthStatusHealthy
+	// EndpointHealthStatusUnhealthy means the endpoint is unhealthy and
+	// should not be used for load balancing.
+	EndpointHealthStatusUnhealthy
+	// EndpointHealthStatusDraining means the endpoint is draining and
+	// should only be used for connections already established to it.
+	EndpointHealthStatusDraining
+	// EndpointHealthStatusTimeout means the endpoint's health check timed
+	// out.
+	EndpointHealthStatusTimeout
+	// EndpointHealthStatusDegraded means the endpoint is degraded and
+	// should be used for load balancing only if no healthy endpoints are
+	// available.
+	EndpointHealthStatusDegraded
+)
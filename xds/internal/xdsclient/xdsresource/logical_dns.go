@@ -0,0 +1,54 @@
+/*
+ *
+ * Copyright 2024 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xdsresource
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// dnsLookupHost resolves a hostname to its A/AAAA records. It is a variable
+// so tests can substitute a fake resolver without making a real DNS query.
+var dnsLookupHost = net.DefaultResolver.LookupHost
+
+// ResolveLogicalDNS expands a ResolverTypeLogicalDNS endpoint's Address into
+// the set of dialable host:port addresses to actually connect to, re-using
+// the endpoint's original port for every resolved IP. This is what makes
+// ResolverTypeLogicalDNS (set by parseAddress for a SocketAddress whose host
+// is a hostname) mean anything: without it, a LOGICAL_DNS endpoint's
+// hostname would just be dialed directly and fail, since a hostname that
+// doesn't resolve via the xds:/// or dns:/// transport isn't dialable as-is.
+func ResolveLogicalDNS(ctx context.Context, ep Endpoint) ([]string, error) {
+	if ep.ResolverType != ResolverTypeLogicalDNS {
+		return nil, fmt.Errorf("endpoint %q is not a LOGICAL_DNS endpoint (ResolverType = %v)", ep.Address, ep.ResolverType)
+	}
+	host, port, err := net.SplitHostPort(ep.Address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOGICAL_DNS endpoint address %q: %v", ep.Address, err)
+	}
+	ips, err := dnsLookupHost(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve LOGICAL_DNS endpoint %q: %v", host, err)
+	}
+	addrs := make([]string, len(ips))
+	for i, ip := range ips {
+		addrs[i] = net.JoinHostPort(ip, port)
+	}
+	return addrs, nil
+}
@@ -0,0 +1,80 @@
+/*
+ *
+ * Copyright 2024 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xdsresource
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestResolveLogicalDNS(t *testing.T) {
+	origLookup := dnsLookupHost
+	defer func() { dnsLookupHost = origLookup }()
+
+	tests := []struct {
+		name    string
+		ep      Endpoint
+		lookup  func(ctx context.Context, host string) ([]string, error)
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "resolves hostname to every returned IP on the endpoint's port",
+			ep:   Endpoint{Address: "example.com:443", ResolverType: ResolverTypeLogicalDNS},
+			lookup: func(ctx context.Context, host string) ([]string, error) {
+				if host != "example.com" {
+					t.Fatalf("lookup host = %q, want %q", host, "example.com")
+				}
+				return []string{"1.2.3.4", "::1"}, nil
+			},
+			want: []string{"1.2.3.4:443", "[::1]:443"},
+		},
+		{
+			name:    "wrong ResolverType is rejected",
+			ep:      Endpoint{Address: "1.2.3.4:443", ResolverType: ResolverTypeSocketIP},
+			wantErr: true,
+		},
+		{
+			name: "lookup failure is propagated",
+			ep:   Endpoint{Address: "example.com:443", ResolverType: ResolverTypeLogicalDNS},
+			lookup: func(ctx context.Context, host string) ([]string, error) {
+				return nil, errors.New("no such host")
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.lookup != nil {
+				dnsLookupHost = tt.lookup
+			}
+			got, err := ResolveLogicalDNS(context.Background(), tt.ep)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveLogicalDNS() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveLogicalDNS() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+/*
+ *
+ * Copyright 2024 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xdsresource
+
+import (
+	"google.golang.org/grpc/internal/grpclog"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// UpdateValidatorFunc performs post-unmarshal validation of a resource that
+// cannot be expressed through the proto alone, e.g. checks that cut across
+// multiple fields or depend on other already-received resources. It is
+// invoked by an `Unmarshal*` function for every successfully parsed
+// resource of that type; a non-nil error fails the resource the same way a
+// parse error would.
+type UpdateValidatorFunc func(any) error
+
+// UnmarshalOptions wraps the input parameters for `Unmarshal*` functions,
+// shared by the LDS/RDS/CDS/EDS resource types. Each `Unmarshal*` function
+// (e.g. UnmarshalEndpoints) walks Resources, producing one resource update
+// per entry.
+type UnmarshalOptions struct {
+	// Version is the version of the response in which the resources were
+	// received, carried through into the resulting UpdateMetadata so
+	// callers can tell which response a cached update came from.
+	Version string
+	// Resources are the raw resources to unmarshal, as received in the
+	// DiscoveryResponse's resources field.
+	Resources []*anypb.Any
+	// Logger is used to log details of the unmarshaling operation.
+	Logger *grpclog.PrefixLogger
+	// UpdateValidator, if set, is applied to each successfully parsed
+	// resource in addition to the type's own proto validation.
+	UpdateValidator UpdateValidatorFunc
+	// Strict, when set, makes an `Unmarshal*` function return as soon as the
+	// first validation failure is found within a resource (the historical
+	// behavior). When unset, all validation failures within a resource are
+	// accumulated and reported together as a *MultiResourceError, and the
+	// resource's update is populated with whatever parsed successfully.
+	Strict bool
+}
@@ -30,6 +30,20 @@ import (
 	"google.golang.org/grpc/internal/pretty"
 	"google.golang.org/grpc/xds/internal"
 	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// envoyLBMetadataKey is the filter metadata namespace, on an LbEndpoint, that
+// carries static load-balancing hints such as ORCA named metrics reported by
+// the client_side_weighted_round_robin and envoy.lb filters.
+const envoyLBMetadataKey = "envoy.lb"
+
+// Well-known keys within the "envoy.lb" filter metadata, mirroring the
+// fields populated by Envoy's client_side_weighted_round_robin LB policy.
+const (
+	metadataKeyAppUtilization = "application_utilization"
+	metadataKeyCPUUtilization = "cpu_utilization"
+	metadataKeyEPS            = "eps"
 )
 
 // UnmarshalEndpoints processes resources received in an EDS response,
@@ -41,7 +55,7 @@ func UnmarshalEndpoints(opts *UnmarshalOptions) (map[string]EndpointsUpdateErrTu
 	return update, md, err
 }
 
-func unmarshalEndpointsResource(r *anypb.Any, logger *grpclog.PrefixLogger) (string, EndpointsUpdate, error) {
+func unmarshalEndpointsResource(r *anypb.Any, opts *UnmarshalOptions) (string, EndpointsUpdate, error) {
 	r, err := unwrapResource(r)
 	if err != nil {
 		return "", EndpointsUpdate{}, fmt.Errorf("failed to unwrap resource: %v", err)
@@ -55,21 +69,62 @@ func unmarshalEndpointsResource(r *anypb.Any, logger *grpclog.PrefixLogger) (str
 	if err := proto.Unmarshal(r.GetValue(), cla); err != nil {
 		return "", EndpointsUpdate{}, fmt.Errorf("failed to unmarshal resource: %v", err)
 	}
+	logger := opts.Logger
 	logger.Infof("Resource with name: %v, type: %T, contains: %v", cla.GetClusterName(), cla, pretty.ToJSON(cla))
 
-	u, err := parseEDSRespProto(cla, logger)
-	if err != nil {
-		return cla.GetClusterName(), EndpointsUpdate{}, err
+	u, errs := parseEDSRespProto(cla, opts.Strict, logger)
+	if len(errs) > 0 {
+		if opts.Strict {
+			return cla.GetClusterName(), EndpointsUpdate{}, errs[0]
+		}
+		u.Raw = r
+		return cla.GetClusterName(), u, &MultiResourceError{Errors: errs}
 	}
 	u.Raw = r
 	return cla.GetClusterName(), u, nil
 }
 
-func parseAddress(socketAddress *v3corepb.SocketAddress) string {
-	return net.JoinHostPort(socketAddress.GetAddress(), strconv.Itoa(int(socketAddress.GetPortValue())))
+// ResolverType indicates how an Endpoint's Address should be resolved into
+// connectable transport addresses.
+type ResolverType int
+
+const (
+	// ResolverTypeSocketIP indicates that Address is already a dialable
+	// host:port (a literal IP, or an xds:/// URI naming an internal
+	// listener) and needs no further resolution.
+	ResolverTypeSocketIP ResolverType = iota
+	// ResolverTypeLogicalDNS indicates that Address is a hostname that must
+	// be expanded to A/AAAA records at pick time, matching Envoy's
+	// LOGICAL_DNS cluster behavior.
+	ResolverTypeLogicalDNS
+)
+
+// parseAddress turns an Endpoint.address proto into a dialable address
+// string and the ResolverType describing how to interpret it. SocketAddress
+// entries whose host is a literal IP are returned as-is; a hostname instead
+// yields ResolverTypeLogicalDNS so the cluster_resolver balancer can start a
+// dns: child resolver for it. An envoy_internal_address is encoded as the
+// xds:///internal/<server_listener_name> URI consumed by the xds transport.
+func parseAddress(addr *v3corepb.Address) (string, ResolverType, error) {
+	if sa := addr.GetSocketAddress(); sa != nil {
+		host := sa.GetAddress()
+		hostport := net.JoinHostPort(host, strconv.Itoa(int(sa.GetPortValue())))
+		if net.ParseIP(host) == nil {
+			return hostport, ResolverTypeLogicalDNS, nil
+		}
+		return hostport, ResolverTypeSocketIP, nil
+	}
+	if eia := addr.GetEnvoyInternalAddress(); eia != nil {
+		name := eia.GetServerListenerName()
+		if name == "" {
+			return "", ResolverTypeSocketIP, fmt.Errorf("endpoint address specifies an envoy_internal_address without a server_listener_name: %+v", addr)
+		}
+		return fmt.Sprintf("xds:///internal/%s", name), ResolverTypeSocketIP, nil
+	}
+	return "", ResolverTypeSocketIP, fmt.Errorf("unsupported endpoint address: %+v", addr)
 }
 
-func parseDropPolicy(dropPolicy *v3endpointpb.ClusterLoadAssignment_Policy_DropOverload) OverloadDropConfig {
+func parseDropPolicy(dropPolicy *v3endpointpb.ClusterLoadAssignment_Policy_DropOverload) (OverloadDropConfig, error) {
 	percentage := dropPolicy.GetDropPercentage()
 	var (
 		numerator   = percentage.GetNumerator()
@@ -82,40 +137,150 @@ func parseDropPolicy(dropPolicy *v3endpointpb.ClusterLoadAssignment_Policy_DropO
 		denominator = 10000
 	case v3typepb.FractionalPercent_MILLION:
 		denominator = 1000000
+	default:
+		return OverloadDropConfig{}, fmt.Errorf("unknown FractionalPercent denominator %v", percentage.GetDenominator())
 	}
 	return OverloadDropConfig{
 		Category:    dropPolicy.GetCategory(),
 		Numerator:   numerator,
 		Denominator: denominator,
+	}, nil
+}
+
+// orcaNamedMetricKeys are the well-known "envoy.lb" metadata keys that feed
+// the typed ApplicationUtilization/CPUUtilization/EPS convenience fields on
+// Endpoint; parseEndpoints reads them back out of Metadata with a float64
+// type assertion, so a non-numeric value here must be rejected up front
+// rather than silently assert-failing into a zero value later.
+var orcaNamedMetricKeys = map[string]bool{
+	metadataKeyAppUtilization: true,
+	metadataKeyCPUUtilization: true,
+	metadataKeyEPS:            true,
+}
+
+// parseEndpointMetadata converts the "envoy.lb" filter metadata on an
+// LbEndpoint into the generic Metadata map carried on Endpoint, additionally
+// populating the typed convenience fields for the ORCA named metrics Envoy's
+// client_side_weighted_round_robin filter writes there. Values of a type we
+// don't recognize are ignored and logged, rather than treated as an error,
+// since this metadata is informational only; the one exception is the
+// well-known ORCA metric keys, which are always logged and dropped if not
+// numeric, since a silently-ignored type mismatch there would otherwise show
+// up only as an inexplicably-zero ApplicationUtilization/CPUUtilization/EPS.
+func parseEndpointMetadata(md *v3corepb.Metadata, logger *grpclog.PrefixLogger) map[string]any {
+	fields := md.GetFilterMetadata()[envoyLBMetadataKey].GetFields()
+	if len(fields) == 0 {
+		return nil
+	}
+	out := make(map[string]any, len(fields))
+	for k, v := range fields {
+		switch v.GetKind().(type) {
+		case *structpb.Value_NumberValue:
+			out[k] = v.GetNumberValue()
+		case *structpb.Value_StringValue:
+			if orcaNamedMetricKeys[k] {
+				logger.Warningf("Ignoring %q field in %q endpoint metadata: want a number, got string %q", k, envoyLBMetadataKey, v.GetStringValue())
+				continue
+			}
+			out[k] = v.GetStringValue()
+		case *structpb.Value_BoolValue:
+			if orcaNamedMetricKeys[k] {
+				logger.Warningf("Ignoring %q field in %q endpoint metadata: want a number, got bool %v", k, envoyLBMetadataKey, v.GetBoolValue())
+				continue
+			}
+			out[k] = v.GetBoolValue()
+		default:
+			logger.Warningf("Ignoring %q field in %q endpoint metadata with unsupported type %T", k, envoyLBMetadataKey, v.GetKind())
+		}
 	}
+	return out
 }
 
-func parseEndpoints(lbEndpoints []*v3endpointpb.LbEndpoint) ([]Endpoint, error) {
+// parseEndpoints parses the LbEndpoints of a single locality, found at
+// localityPath within the CLA. In strict mode, it returns as soon as the
+// first invalid endpoint is found. Otherwise it skips invalid endpoints,
+// collects a ResourceError for each, and returns the endpoints that did
+// parse cleanly alongside the errors.
+func parseEndpoints(clusterName, localityPath string, lbEndpoints []*v3endpointpb.LbEndpoint, strict bool, logger *grpclog.PrefixLogger) ([]Endpoint, []*ResourceError) {
 	endpoints := make([]Endpoint, 0, len(lbEndpoints))
-	for _, lbEndpoint := range lbEndpoints {
+	var errs []*ResourceError
+	for i, lbEndpoint := range lbEndpoints {
+		path := fmt.Sprintf("%s/lb_endpoints/%d", localityPath, i)
 		weight := lbEndpoint.GetLoadBalancingWeight().GetValue()
 		if weight == 0 {
-			return nil, fmt.Errorf("EDS response contains an endpoint with zero weight: %+v", lbEndpoint)
+			rerr := &ResourceError{Name: clusterName, Path: path + "/load_balancing_weight", Code: ResourceErrorCodeZeroWeightEndpoint, Detail: fmt.Sprintf("endpoint has zero weight: %+v", lbEndpoint)}
+			if strict {
+				return nil, []*ResourceError{rerr}
+			}
+			errs = append(errs, rerr)
+			continue
 		}
-		endpoints = append(endpoints, Endpoint{
-			HealthStatus: EndpointHealthStatus(lbEndpoint.GetHealthStatus()),
-			Address:      parseAddress(lbEndpoint.GetEndpoint().GetAddress().GetSocketAddress()),
-			Weight:       weight,
-		})
+		address, resolverType, err := parseAddress(lbEndpoint.GetEndpoint().GetAddress())
+		if err != nil {
+			rerr := &ResourceError{Name: clusterName, Path: path + "/endpoint/address", Code: ResourceErrorCodeInvalidAddress, Detail: err.Error()}
+			if strict {
+				return nil, []*ResourceError{rerr}
+			}
+			errs = append(errs, rerr)
+			continue
+		}
+		endpoint := Endpoint{
+			HealthStatus:    EndpointHealthStatus(lbEndpoint.GetHealthStatus()),
+			Address:         address,
+			ResolverType:    resolverType,
+			Weight:          weight,
+			Metadata:        parseEndpointMetadata(lbEndpoint.GetMetadata(), logger),
+			Hostname:        lbEndpoint.GetEndpoint().GetHostname(),
+			HealthCheckPort: lbEndpoint.GetEndpoint().GetHealthCheckConfig().GetPortValue(),
+		}
+		if v, ok := endpoint.Metadata[metadataKeyAppUtilization].(float64); ok {
+			endpoint.ApplicationUtilization = v
+		}
+		if v, ok := endpoint.Metadata[metadataKeyCPUUtilization].(float64); ok {
+			endpoint.CPUUtilization = v
+		}
+		if v, ok := endpoint.Metadata[metadataKeyEPS].(float64); ok {
+			endpoint.EPS = v
+		}
+		endpoints = append(endpoints, endpoint)
 	}
-	return endpoints, nil
+	return endpoints, errs
 }
 
-func parseEDSRespProto(m *v3endpointpb.ClusterLoadAssignment, logger *grpclog.PrefixLogger) (EndpointsUpdate, error) {
+// parseEDSRespProto parses and validates a ClusterLoadAssignment. In strict
+// mode it returns as soon as the first validation failure is found,
+// preserving the historical fail-fast behavior. Otherwise it accumulates a
+// ResourceError for every failure found (zero-weight endpoints, missing
+// locality IDs, duplicate localities, priority gaps, malformed addresses,
+// ...) and returns the partial EndpointsUpdate built from whatever parsed
+// cleanly alongside the full list of errors.
+func parseEDSRespProto(m *v3endpointpb.ClusterLoadAssignment, strict bool, logger *grpclog.PrefixLogger) (EndpointsUpdate, []*ResourceError) {
 	ret := EndpointsUpdate{}
-	for _, dropPolicy := range m.GetPolicy().GetDropOverloads() {
-		ret.Drops = append(ret.Drops, parseDropPolicy(dropPolicy))
+	clusterName := m.GetClusterName()
+	var errs []*ResourceError
+	for i, dropPolicy := range m.GetPolicy().GetDropOverloads() {
+		drop, err := parseDropPolicy(dropPolicy)
+		if err != nil {
+			rerr := &ResourceError{Name: clusterName, Path: fmt.Sprintf("/policy/drop_overloads/%d/drop_percentage", i), Code: ResourceErrorCodeInvalidFractionalPercent, Detail: err.Error()}
+			if strict {
+				return EndpointsUpdate{}, []*ResourceError{rerr}
+			}
+			errs = append(errs, rerr)
+			continue
+		}
+		ret.Drops = append(ret.Drops, drop)
 	}
 	priorities := make(map[uint32]map[string]bool)
-	for _, locality := range m.Endpoints {
+	for i, locality := range m.Endpoints {
+		localityPath := fmt.Sprintf("/endpoints/%d", i)
 		l := locality.GetLocality()
 		if l == nil {
-			return EndpointsUpdate{}, fmt.Errorf("EDS response contains a locality without ID, locality: %+v", locality)
+			rerr := &ResourceError{Name: clusterName, Path: localityPath + "/locality", Code: ResourceErrorCodeMissingLocalityID, Detail: fmt.Sprintf("locality has no ID: %+v", locality)}
+			if strict {
+				return EndpointsUpdate{}, []*ResourceError{rerr}
+			}
+			errs = append(errs, rerr)
+			continue
 		}
 		weight := locality.GetLoadBalancingWeight().GetValue()
 		if weight == 0 {
@@ -135,12 +300,45 @@ func parseEDSRespProto(m *v3endpointpb.ClusterLoadAssignment, logger *grpclog.Pr
 		}
 		lidStr, _ := lid.ToString()
 		if localitiesWithPriority[lidStr] {
-			return EndpointsUpdate{}, fmt.Errorf("duplicate locality %s with the same priority %v", lidStr, priority)
+			rerr := &ResourceError{Name: clusterName, Path: localityPath + "/locality", Code: ResourceErrorCodeDuplicateLocality, Detail: fmt.Sprintf("duplicate locality %s with the same priority %v", lidStr, priority)}
+			if strict {
+				return EndpointsUpdate{}, []*ResourceError{rerr}
+			}
+			errs = append(errs, rerr)
+			continue
 		}
 		localitiesWithPriority[lidStr] = true
-		endpoints, err := parseEndpoints(locality.GetLbEndpoints())
-		if err != nil {
-			return EndpointsUpdate{}, err
+
+		// A locality carrying a LEDS collection reference has its endpoints
+		// delivered out-of-band via delta-xDS, rather than inline in this
+		// CLA. Record the collection name so the xdsClient can start a
+		// subscription for it; the locality's Endpoints slice is populated
+		// (and kept up to date) by the LEDS watcher instead of here.
+		if ledsConfig := locality.GetLedsClusterLocalityConfig(); ledsConfig != nil {
+			collection := ledsConfig.GetLedsCollectionName()
+			if collection == "" {
+				rerr := &ResourceError{Name: clusterName, Path: localityPath + "/leds_cluster_locality_config", Code: ResourceErrorCodeMissingLEDSCollection, Detail: fmt.Sprintf("locality has an leds_cluster_locality_config but no leds_collection_name: %+v", locality)}
+				if strict {
+					return EndpointsUpdate{}, []*ResourceError{rerr}
+				}
+				errs = append(errs, rerr)
+				continue
+			}
+			ret.Localities = append(ret.Localities, Locality{
+				ID:             lid,
+				Weight:         weight,
+				Priority:       priority,
+				LEDSCollection: collection,
+			})
+			continue
+		}
+
+		endpoints, eerrs := parseEndpoints(clusterName, localityPath, locality.GetLbEndpoints(), strict, logger)
+		if len(eerrs) > 0 {
+			if strict {
+				return EndpointsUpdate{}, eerrs
+			}
+			errs = append(errs, eerrs...)
 		}
 		ret.Localities = append(ret.Localities, Locality{
 			ID:        lid,
@@ -151,8 +349,12 @@ func parseEDSRespProto(m *v3endpointpb.ClusterLoadAssignment, logger *grpclog.Pr
 	}
 	for i := 0; i < len(priorities); i++ {
 		if _, ok := priorities[uint32(i)]; !ok {
-			return EndpointsUpdate{}, fmt.Errorf("priority %v missing (with different priorities %v received)", i, priorities)
+			rerr := &ResourceError{Name: clusterName, Path: "/endpoints", Code: ResourceErrorCodeMissingPriority, Detail: fmt.Sprintf("priority %v missing (with different priorities %v received)", i, priorities)}
+			if strict {
+				return EndpointsUpdate{}, []*ResourceError{rerr}
+			}
+			errs = append(errs, rerr)
 		}
 	}
-	return ret, nil
+	return ret, errs
 }
@@ -0,0 +1,260 @@
+/*
+ *
+ * Copyright 2024 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xdsresource
+
+import (
+	"testing"
+
+	v3corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	v3endpointpb "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	v3typepb "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"google.golang.org/grpc/internal/grpclog"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		name             string
+		addr             *v3corepb.Address
+		wantAddr         string
+		wantResolverType ResolverType
+		wantErr          bool
+	}{
+		{
+			name:             "literal IP socket address",
+			addr:             &v3corepb.Address{Address: &v3corepb.Address_SocketAddress{SocketAddress: &v3corepb.SocketAddress{Address: "1.2.3.4", PortSpecifier: &v3corepb.SocketAddress_PortValue{PortValue: 8080}}}},
+			wantAddr:         "1.2.3.4:8080",
+			wantResolverType: ResolverTypeSocketIP,
+		},
+		{
+			name:             "hostname socket address needs LOGICAL_DNS resolution",
+			addr:             &v3corepb.Address{Address: &v3corepb.Address_SocketAddress{SocketAddress: &v3corepb.SocketAddress{Address: "example.com", PortSpecifier: &v3corepb.SocketAddress_PortValue{PortValue: 443}}}},
+			wantAddr:         "example.com:443",
+			wantResolverType: ResolverTypeLogicalDNS,
+		},
+		{
+			name:             "envoy internal address becomes an xds:/// URI",
+			addr:             &v3corepb.Address{Address: &v3corepb.Address_EnvoyInternalAddress{EnvoyInternalAddress: &v3corepb.EnvoyInternalAddress{AddressNameSpecifier: &v3corepb.EnvoyInternalAddress_ServerListenerName{ServerListenerName: "listener1"}}}},
+			wantAddr:         "xds:///internal/listener1",
+			wantResolverType: ResolverTypeSocketIP,
+		},
+		{
+			name:    "envoy internal address without a server_listener_name is an error",
+			addr:    &v3corepb.Address{Address: &v3corepb.Address_EnvoyInternalAddress{EnvoyInternalAddress: &v3corepb.EnvoyInternalAddress{}}},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported address kind is an error",
+			addr:    &v3corepb.Address{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotAddr, gotResolverType, err := parseAddress(tt.addr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAddress() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if gotAddr != tt.wantAddr || gotResolverType != tt.wantResolverType {
+				t.Errorf("parseAddress() = (%q, %v), want (%q, %v)", gotAddr, gotResolverType, tt.wantAddr, tt.wantResolverType)
+			}
+		})
+	}
+}
+
+func TestParseEDSRespProtoAccumulatesErrors(t *testing.T) {
+	logger := grpclog.NewPrefixLogger(nil, "xdsresource-test: ")
+	cla := &v3endpointpb.ClusterLoadAssignment{
+		ClusterName: "cluster1",
+		Policy: &v3endpointpb.ClusterLoadAssignment_Policy{
+			DropOverloads: []*v3endpointpb.ClusterLoadAssignment_Policy_DropOverload{
+				{Category: "cat1", DropPercentage: &v3typepb.FractionalPercent{Numerator: 1, Denominator: 99}},
+			},
+		},
+		Endpoints: []*v3endpointpb.LocalityLbEndpoints{
+			{ // no Locality: missing-locality-ID error.
+				Priority: 0,
+			},
+			{ // zero-weight endpoint error, at priority 1 (leaving priority 0 absent among valid localities).
+				Locality:            &v3corepb.Locality{SubZone: "z1"},
+				Priority:            1,
+				LoadBalancingWeight: &wrapperspb.UInt32Value{Value: 1},
+				LbEndpoints: []*v3endpointpb.LbEndpoint{
+					{LoadBalancingWeight: &wrapperspb.UInt32Value{Value: 0}},
+				},
+			},
+			{ // duplicate of the z1/priority-1 locality above.
+				Locality:            &v3corepb.Locality{SubZone: "z1"},
+				Priority:            1,
+				LoadBalancingWeight: &wrapperspb.UInt32Value{Value: 1},
+			},
+		},
+	}
+
+	t.Run("non-strict accumulates every failure", func(t *testing.T) {
+		_, errs := parseEDSRespProto(cla, false /* strict */, logger)
+		wantCodes := []ResourceErrorCode{
+			ResourceErrorCodeInvalidFractionalPercent,
+			ResourceErrorCodeMissingLocalityID,
+			ResourceErrorCodeZeroWeightEndpoint,
+			ResourceErrorCodeDuplicateLocality,
+			ResourceErrorCodeMissingPriority,
+		}
+		if len(errs) != len(wantCodes) {
+			t.Fatalf("parseEDSRespProto() returned %d errors, want %d: %v", len(errs), len(wantCodes), errs)
+		}
+		for i, want := range wantCodes {
+			if errs[i].Code != want {
+				t.Errorf("errs[%d].Code = %v, want %v (%v)", i, errs[i].Code, want, errs[i])
+			}
+		}
+	})
+
+	t.Run("strict fails fast on the first failure", func(t *testing.T) {
+		_, errs := parseEDSRespProto(cla, true /* strict */, logger)
+		if len(errs) != 1 || errs[0].Code != ResourceErrorCodeInvalidFractionalPercent {
+			t.Fatalf("parseEDSRespProto() in strict mode = %v, want a single ResourceErrorCodeInvalidFractionalPercent", errs)
+		}
+	})
+}
+
+func TestParseEDSRespProtoLEDSLocality(t *testing.T) {
+	logger := grpclog.NewPrefixLogger(nil, "xdsresource-test: ")
+
+	t.Run("locality with a LEDS collection gets LEDSCollection set and no inline endpoints", func(t *testing.T) {
+		cla := &v3endpointpb.ClusterLoadAssignment{
+			ClusterName: "cluster1",
+			Endpoints: []*v3endpointpb.LocalityLbEndpoints{
+				{
+					Locality:            &v3corepb.Locality{SubZone: "z1"},
+					LoadBalancingWeight: &wrapperspb.UInt32Value{Value: 1},
+					LbConfig: &v3endpointpb.LocalityLbEndpoints_LedsClusterLocalityConfig{
+						LedsClusterLocalityConfig: &v3endpointpb.LedsClusterLocalityConfig{LedsCollectionName: "collection-a"},
+					},
+				},
+			},
+		}
+		got, errs := parseEDSRespProto(cla, false /* strict */, logger)
+		if len(errs) != 0 {
+			t.Fatalf("parseEDSRespProto() errs = %v, want none", errs)
+		}
+		if len(got.Localities) != 1 {
+			t.Fatalf("parseEDSRespProto() got %d localities, want 1", len(got.Localities))
+		}
+		loc := got.Localities[0]
+		if loc.LEDSCollection != "collection-a" {
+			t.Errorf("Localities[0].LEDSCollection = %q, want %q", loc.LEDSCollection, "collection-a")
+		}
+		if loc.Endpoints != nil {
+			t.Errorf("Localities[0].Endpoints = %v, want nil (delivered out-of-band via LEDS)", loc.Endpoints)
+		}
+	})
+
+	t.Run("LEDS collection without a name is a validation error", func(t *testing.T) {
+		cla := &v3endpointpb.ClusterLoadAssignment{
+			ClusterName: "cluster1",
+			Endpoints: []*v3endpointpb.LocalityLbEndpoints{
+				{
+					Locality:            &v3corepb.Locality{SubZone: "z1"},
+					LoadBalancingWeight: &wrapperspb.UInt32Value{Value: 1},
+					LbConfig: &v3endpointpb.LocalityLbEndpoints_LedsClusterLocalityConfig{
+						LedsClusterLocalityConfig: &v3endpointpb.LedsClusterLocalityConfig{},
+					},
+				},
+			},
+		}
+		_, errs := parseEDSRespProto(cla, false /* strict */, logger)
+		if len(errs) != 1 || errs[0].Code != ResourceErrorCodeMissingLEDSCollection {
+			t.Fatalf("parseEDSRespProto() errs = %v, want a single ResourceErrorCodeMissingLEDSCollection", errs)
+		}
+	})
+}
+
+func metadataWithFields(t *testing.T, fields map[string]*structpb.Value) *v3corepb.Metadata {
+	t.Helper()
+	return &v3corepb.Metadata{
+		FilterMetadata: map[string]*structpb.Struct{
+			envoyLBMetadataKey: {Fields: fields},
+		},
+	}
+}
+
+func TestParseEndpointMetadata(t *testing.T) {
+	logger := grpclog.NewPrefixLogger(nil, "xdsresource-test: ")
+
+	tests := []struct {
+		name   string
+		fields map[string]*structpb.Value
+		want   map[string]any
+	}{
+		{
+			name: "well-known numeric keys pass through",
+			fields: map[string]*structpb.Value{
+				metadataKeyAppUtilization: structpb.NewNumberValue(0.5),
+				metadataKeyCPUUtilization: structpb.NewNumberValue(0.25),
+				metadataKeyEPS:            structpb.NewNumberValue(10),
+			},
+			want: map[string]any{
+				metadataKeyAppUtilization: 0.5,
+				metadataKeyCPUUtilization: 0.25,
+				metadataKeyEPS:            10.0,
+			},
+		},
+		{
+			name: "non-numeric value for a well-known key is dropped, not silently zeroed",
+			fields: map[string]*structpb.Value{
+				metadataKeyAppUtilization: structpb.NewStringValue("high"),
+				metadataKeyEPS:            structpb.NewBoolValue(true),
+			},
+			want: map[string]any{},
+		},
+		{
+			name: "arbitrary string and bool metadata pass through unchanged",
+			fields: map[string]*structpb.Value{
+				"custom_string": structpb.NewStringValue("v"),
+				"custom_bool":   structpb.NewBoolValue(true),
+			},
+			want: map[string]any{"custom_string": "v", "custom_bool": true},
+		},
+		{
+			name: "list and struct values are ignored",
+			fields: map[string]*structpb.Value{
+				"custom_list":   structpb.NewListValue(&structpb.ListValue{}),
+				"custom_struct": structpb.NewStructValue(&structpb.Struct{}),
+			},
+			want: map[string]any{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseEndpointMetadata(metadataWithFields(t, tt.fields), logger)
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseEndpointMetadata() = %+v, want %+v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("parseEndpointMetadata()[%q] = %v, want %v", k, got[k], v)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+/*
+ *
+ * Copyright 2024 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xdsresource
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	v3endpointpb "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/internal/grpclog"
+	"google.golang.org/grpc/internal/pretty"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// version3LbEndpointURL is the resource type URL for the LbEndpoint resource
+// used by LEDS (Leaf Endpoint Discovery Service). Unlike the other xDS
+// resource types, it is only ever fetched via Delta-xDS, as a leaf member of
+// an EDS locality's leds_cluster_locality_config collection.
+const version3LbEndpointURL = "type.googleapis.com/envoy.config.endpoint.v3.LbEndpoint"
+
+// IsLbEndpointResource returns true if the given resource-type URL is that of
+// the LbEndpoint resource, as used by LEDS.
+func IsLbEndpointResource(url string) bool {
+	return url == version3LbEndpointURL
+}
+
+// LbEndpointUpdate is the resource update for a single LbEndpoint resource
+// delivered via a LEDS (Leaf Endpoint Discovery Service) delta-xDS
+// subscription. It carries enough information for the EDS watcher to merge
+// the endpoint into the Locality it was requested for.
+type LbEndpointUpdate struct {
+	// Endpoint is the parsed representation of the single LbEndpoint carried
+	// by this resource. It is the zero value when the resource has been
+	// removed from the LEDS collection.
+	Endpoint Endpoint
+	// Removed is true when this update represents the removal of the
+	// endpoint from its LEDS collection, rather than an add or a change.
+	Removed bool
+
+	// Raw is the resource data included in the xDS response, for debugging
+	// purposes.
+	Raw *anypb.Any
+}
+
+// UnmarshalLbEndpoint processes a single LbEndpoint resource received over a
+// Delta-xDS LEDS subscription, validates it, and transforms it into a native
+// struct. name is the resource's Delta-xDS name, as handed down by the ADS
+// stream handling (the wire resource name, not anything derived from the
+// LbEndpoint content itself) - it is of the form
+// "<leds_collection_name>/<endpoint_id>", which the caller uses to look up
+// the Locality this endpoint belongs to and merge it into
+// Locality.Endpoints. This mirrors UnmarshalEndpoints, which is the
+// equivalent SotW entry point for the EDS resource type.
+func UnmarshalLbEndpoint(name string, r *anypb.Any, logger *grpclog.PrefixLogger) (string, LbEndpointUpdate, error) {
+	return unmarshalLbEndpointResource(name, r, logger)
+}
+
+// ledsEndpointID returns the "<endpoint_id>" portion of a LEDS resource name
+// of the form "<leds_collection_name>/<endpoint_id>", for logging purposes.
+func ledsEndpointID(name string) string {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// unmarshalLbEndpointResource unmarshals a single LbEndpoint resource, as
+// delivered by LEDS. name is the Delta-xDS resource name supplied by the
+// transport (the LEDS collection entry name, "<leds_collection_name>/
+// <endpoint_id>"); it is returned unchanged so the caller can map this
+// update back to the locality/collection it belongs to.
+func unmarshalLbEndpointResource(name string, r *anypb.Any, logger *grpclog.PrefixLogger) (string, LbEndpointUpdate, error) {
+	r, err := unwrapResource(r)
+	if err != nil {
+		return name, LbEndpointUpdate{}, fmt.Errorf("failed to unwrap resource: %v", err)
+	}
+
+	if !IsLbEndpointResource(r.GetTypeUrl()) {
+		return name, LbEndpointUpdate{}, fmt.Errorf("unexpected resource type: %q ", r.GetTypeUrl())
+	}
+
+	lbEndpoint := &v3endpointpb.LbEndpoint{}
+	if err := proto.Unmarshal(r.GetValue(), lbEndpoint); err != nil {
+		return name, LbEndpointUpdate{}, fmt.Errorf("failed to unmarshal resource: %v", err)
+	}
+	logger.Infof("Resource %q with type: %T, contains: %v", ledsEndpointID(name), lbEndpoint, pretty.ToJSON(lbEndpoint))
+
+	endpoints, errs := parseEndpoints(name, "/lb_endpoint", []*v3endpointpb.LbEndpoint{lbEndpoint}, true /* strict */, logger)
+	if len(errs) > 0 {
+		return name, LbEndpointUpdate{}, errs[0]
+	}
+	return name, LbEndpointUpdate{Endpoint: endpoints[0], Raw: r}, nil
+}
+
+// LEDSEndpointSet accumulates the individual LbEndpoint updates delivered
+// over a single LEDS delta-xDS subscription (one per leds_collection_name),
+// keyed by endpoint_id, and flattens them into the Endpoints slice of the
+// Locality that references the collection. The EDS watcher keeps one
+// LEDSEndpointSet per subscribed collection and calls MergeLEDSCollection after every
+// Merge to refresh the combined EndpointsUpdate handed to the balancer.
+type LEDSEndpointSet struct {
+	mu        sync.Mutex
+	endpoints map[string]Endpoint // endpoint_id -> parsed endpoint
+}
+
+// NewLEDSEndpointSet returns an empty LEDSEndpointSet.
+func NewLEDSEndpointSet() *LEDSEndpointSet {
+	return &LEDSEndpointSet{endpoints: make(map[string]Endpoint)}
+}
+
+// Merge applies a single LbEndpoint update to the set. name is the Delta-xDS
+// resource name the update was received under, "<leds_collection_name>/
+// <endpoint_id>" (the same name passed to UnmarshalLbEndpoint); only its
+// endpoint_id suffix is used here, since a set is already scoped to one
+// collection.
+func (s *LEDSEndpointSet) Merge(name string, upd LbEndpointUpdate) {
+	id := ledsEndpointID(name)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if upd.Removed {
+		delete(s.endpoints, id)
+		return
+	}
+	s.endpoints[id] = upd.Endpoint
+}
+
+// Endpoints returns the set's current endpoints, ordered by endpoint_id so
+// that repeated calls produce a stable Locality.Endpoints slice (and so
+// EndpointsUpdate.Equal doesn't see spurious reordering as a change).
+func (s *LEDSEndpointSet) Endpoints() []Endpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, 0, len(s.endpoints))
+	for id := range s.endpoints {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	eps := make([]Endpoint, len(ids))
+	for i, id := range ids {
+		eps[i] = s.endpoints[id]
+	}
+	return eps
+}
+
+// MergeLEDSCollection copies the current contents of set into whichever
+// Locality of u has a matching LEDSCollection, replacing its Endpoints. It
+// reports false if no locality in u references collection, so the caller
+// can tell a stale collection update apart from one that actually applies.
+func MergeLEDSCollection(u *EndpointsUpdate, collection string, set *LEDSEndpointSet) bool {
+	for i := range u.Localities {
+		if u.Localities[i].LEDSCollection != collection {
+			continue
+		}
+		u.Localities[i].Endpoints = set.Endpoints()
+		return true
+	}
+	return false
+}
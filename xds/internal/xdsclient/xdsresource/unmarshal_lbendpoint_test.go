@@ -0,0 +1,79 @@
+/*
+ *
+ * Copyright 2024 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xdsresource
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/xds/internal"
+)
+
+func TestLEDSEndpointSetMerge(t *testing.T) {
+	s := NewLEDSEndpointSet()
+	s.Merge("collection-a/ep-2", LbEndpointUpdate{Endpoint: Endpoint{Address: "2.2.2.2:80"}})
+	s.Merge("collection-a/ep-1", LbEndpointUpdate{Endpoint: Endpoint{Address: "1.1.1.1:80"}})
+
+	got := s.Endpoints()
+	want := []Endpoint{{Address: "1.1.1.1:80"}, {Address: "2.2.2.2:80"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Endpoints() = %+v, want %+v (ordered by endpoint_id)", got, want)
+	}
+
+	// A change to ep-1 replaces its entry in place.
+	s.Merge("collection-a/ep-1", LbEndpointUpdate{Endpoint: Endpoint{Address: "1.1.1.1:81"}})
+	got = s.Endpoints()
+	want = []Endpoint{{Address: "1.1.1.1:81"}, {Address: "2.2.2.2:80"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Endpoints() after update = %+v, want %+v", got, want)
+	}
+
+	// A removal drops the entry entirely, not just zeroing it.
+	s.Merge("collection-a/ep-2", LbEndpointUpdate{Removed: true})
+	got = s.Endpoints()
+	want = []Endpoint{{Address: "1.1.1.1:81"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Endpoints() after removal = %+v, want %+v", got, want)
+	}
+}
+
+func TestMergeLEDSCollection(t *testing.T) {
+	u := &EndpointsUpdate{
+		Localities: []Locality{
+			{ID: internal.LocalityID{SubZone: "locality-no-leds"}, Endpoints: []Endpoint{{Address: "0.0.0.0:1"}}},
+			{ID: internal.LocalityID{SubZone: "locality-leds"}, LEDSCollection: "collection-a"},
+		},
+	}
+	set := NewLEDSEndpointSet()
+	set.Merge("collection-a/ep-1", LbEndpointUpdate{Endpoint: Endpoint{Address: "1.1.1.1:80"}})
+
+	if ok := MergeLEDSCollection(u, "collection-a", set); !ok {
+		t.Fatalf("MergeLEDSCollection(_, %q, _) = false, want true", "collection-a")
+	}
+	want := []Endpoint{{Address: "1.1.1.1:80"}}
+	if !reflect.DeepEqual(u.Localities[1].Endpoints, want) {
+		t.Errorf("locality-leds.Endpoints = %+v, want %+v", u.Localities[1].Endpoints, want)
+	}
+	if len(u.Localities[0].Endpoints) != 1 {
+		t.Errorf("locality-no-leds.Endpoints was mutated: %+v", u.Localities[0].Endpoints)
+	}
+
+	if ok := MergeLEDSCollection(u, "collection-does-not-exist", set); ok {
+		t.Errorf("MergeLEDSCollection(_, %q, _) = true, want false", "collection-does-not-exist")
+	}
+}